@@ -0,0 +1,28 @@
+package ptouch
+
+import "fmt"
+
+// CompressionMode selects how PackRasterLines frames raster data.
+type CompressionMode byte
+
+const (
+	// CompressionNone sends each raster line as a fixed-length, uncompressed
+	// bitmap.
+	CompressionNone CompressionMode = 0x00
+	// CompressionRLE PackBits-compresses each raster line, which typically
+	// halves data volume for labels that are mostly whitespace.
+	CompressionRLE CompressionMode = 0x02
+)
+
+// SetCompression tells the printer which raster line framing to expect, and
+// records the mode so PackRasterLines matches it. It must be called before
+// PackRasterLines/PrintRaster for a job using a different mode than the
+// last one set.
+func (p *Printer) SetCompression(mode CompressionMode) error {
+	cmd := []byte{'M', byte(mode)}
+	if err := p.write(cmd); err != nil {
+		return fmt.Errorf("unable to set compression mode 0x%02X: %v", byte(mode), err)
+	}
+	p.compression = mode
+	return nil
+}
@@ -0,0 +1,278 @@
+package ptouch
+
+import "fmt"
+
+// ErrorFlag identifies a single error condition reported in a status reply's
+// two error bitfields.
+type ErrorFlag int
+
+const (
+	ErrorNoMedia ErrorFlag = iota
+	ErrorEndOfMedia
+	ErrorCutterJam
+	ErrorPrinterInUse
+	ErrorHighVoltageAdapter
+	ErrorFanMotor
+	ErrorReplaceMedia
+	ErrorExpansionBufferFull
+	ErrorCommunicationError
+	ErrorCommunicationBufferFull
+	ErrorCoverOpen
+	ErrorCancelKey
+	ErrorCannotFeed
+	ErrorSystemError
+)
+
+func (e ErrorFlag) String() string {
+	switch e {
+	case ErrorNoMedia:
+		return "no media"
+	case ErrorEndOfMedia:
+		return "end of media"
+	case ErrorCutterJam:
+		return "cutter jam"
+	case ErrorPrinterInUse:
+		return "printer in use"
+	case ErrorHighVoltageAdapter:
+		return "high-voltage adapter"
+	case ErrorFanMotor:
+		return "fan motor error"
+	case ErrorReplaceMedia:
+		return "replace media"
+	case ErrorExpansionBufferFull:
+		return "expansion buffer full"
+	case ErrorCommunicationError:
+		return "communication error"
+	case ErrorCommunicationBufferFull:
+		return "communication buffer full"
+	case ErrorCoverOpen:
+		return "cover open"
+	case ErrorCancelKey:
+		return "cancel key pressed"
+	case ErrorCannotFeed:
+		return "cannot feed media"
+	case ErrorSystemError:
+		return "system error"
+	}
+	return fmt.Sprintf("unknown error flag %d", int(e))
+}
+
+// errorFlag pairs a bit position in a status reply's error byte with the
+// ErrorFlag it signals.
+type errorFlag struct {
+	bit byte
+	e   ErrorFlag
+}
+
+// error1Flags and error2Flags list, in bit order (LSB first), the flags in
+// the status reply's two error bytes. Slices (rather than maps) keep
+// decodeStatus's output order deterministic, so the same fault always
+// produces the same Errors ordering.
+var error1Flags = []errorFlag{
+	{0x01, ErrorNoMedia},
+	{0x02, ErrorEndOfMedia},
+	{0x04, ErrorCutterJam},
+	{0x10, ErrorPrinterInUse},
+	{0x40, ErrorHighVoltageAdapter},
+	{0x80, ErrorFanMotor},
+}
+
+var error2Flags = []errorFlag{
+	{0x01, ErrorReplaceMedia},
+	{0x02, ErrorExpansionBufferFull},
+	{0x04, ErrorCommunicationError},
+	{0x08, ErrorCommunicationBufferFull},
+	{0x10, ErrorCoverOpen},
+	{0x20, ErrorCancelKey},
+	{0x40, ErrorCannotFeed},
+	{0x80, ErrorSystemError},
+}
+
+// MediaType identifies the kind of tape or label media loaded.
+type MediaType int
+
+const (
+	MediaTypeUnknown MediaType = iota
+	MediaTypeNone
+	MediaTypeContinuous
+	MediaTypeDieCut
+	MediaTypeHeatShrink
+)
+
+func mediaTypeFromByte(b byte) MediaType {
+	switch b {
+	case 0x00:
+		return MediaTypeNone
+	case 0x0A:
+		return MediaTypeContinuous
+	case 0x0B:
+		return MediaTypeDieCut
+	case 0x11:
+		return MediaTypeHeatShrink
+	}
+	return MediaTypeUnknown
+}
+
+func (m MediaType) String() string {
+	switch m {
+	case MediaTypeNone:
+		return "no media"
+	case MediaTypeContinuous:
+		return "continuous tape"
+	case MediaTypeDieCut:
+		return "die-cut labels"
+	case MediaTypeHeatShrink:
+		return "heat-shrink tube"
+	}
+	return "unknown media"
+}
+
+// StatusType identifies why the printer sent a status reply.
+type StatusType int
+
+const (
+	StatusTypeUnknown StatusType = iota
+	StatusTypeReplyToRequest
+	StatusTypePrintingCompleted
+	StatusTypeError
+	StatusTypeExitIF
+	StatusTypeTurnedOff
+	StatusTypeNotification
+	StatusTypePhaseChange
+)
+
+func statusTypeFromByte(b byte) StatusType {
+	switch b {
+	case 0x00:
+		return StatusTypeReplyToRequest
+	case 0x01:
+		return StatusTypePrintingCompleted
+	case 0x02:
+		return StatusTypeError
+	case 0x03:
+		return StatusTypeExitIF
+	case 0x04:
+		return StatusTypeTurnedOff
+	case 0x05:
+		return StatusTypeNotification
+	case 0x06:
+		return StatusTypePhaseChange
+	}
+	return StatusTypeUnknown
+}
+
+func (t StatusType) String() string {
+	switch t {
+	case StatusTypeReplyToRequest:
+		return "reply to status request"
+	case StatusTypePrintingCompleted:
+		return "printing completed"
+	case StatusTypeError:
+		return "error occurred"
+	case StatusTypeExitIF:
+		return "IF mode exited"
+	case StatusTypeTurnedOff:
+		return "turned off"
+	case StatusTypeNotification:
+		return "notification"
+	case StatusTypePhaseChange:
+		return "phase change"
+	}
+	return "unknown status type"
+}
+
+// Phase identifies the printer's current high-level activity.
+type Phase int
+
+const (
+	PhaseReceiving Phase = iota
+	PhasePrinting
+)
+
+func phaseFromByte(b byte) Phase {
+	if b == 0x01 {
+		return PhasePrinting
+	}
+	return PhaseReceiving
+}
+
+func (p Phase) String() string {
+	if p == PhasePrinting {
+		return "printing"
+	}
+	return "receiving"
+}
+
+// Notification identifies the event that produced a StatusTypeNotification
+// status reply.
+type Notification int
+
+const (
+	NotificationNone Notification = iota
+	NotificationCoverOpen
+	NotificationCoverClosed
+)
+
+func notificationFromByte(b byte) Notification {
+	switch b {
+	case 0x01:
+		return NotificationCoverOpen
+	case 0x02:
+		return NotificationCoverClosed
+	}
+	return NotificationNone
+}
+
+func (n Notification) String() string {
+	switch n {
+	case NotificationCoverOpen:
+		return "cover opened"
+	case NotificationCoverClosed:
+		return "cover closed"
+	}
+	return "no notification"
+}
+
+// Status is the decoded form of a printer status reply.
+type Status struct {
+	Model         Model
+	Errors        []ErrorFlag
+	MediaWidthMM  int
+	MediaLengthMM int
+	MediaType     MediaType
+	StatusType    StatusType
+	Phase         Phase
+	PhaseNumber   uint16
+	Notification  Notification
+}
+
+// decodeStatus turns a raw wire-format status reply into a Status. model is
+// the printer's already-known model (from device ID probing), since the
+// status reply's model byte is Brother-internal and not worth exposing
+// separately.
+func decodeStatus(s *rawStatus, model Model) (*Status, error) {
+	if err := checkFixedFields(s); err != nil {
+		return nil, err
+	}
+	d := &Status{
+		Model:         model,
+		MediaWidthMM:  int(s.MediaWidth),
+		MediaLengthMM: int(s.MediaLength),
+		MediaType:     mediaTypeFromByte(s.Mediatype),
+		StatusType:    statusTypeFromByte(s.StatusType),
+		Phase:         phaseFromByte(s.PhaseType),
+		PhaseNumber:   uint16(s.PhaseHigh)<<8 | uint16(s.PhaseLow),
+		Notification:  notificationFromByte(s.NotifNum),
+	}
+	for _, f := range error1Flags {
+		if s.Error1&f.bit != 0 {
+			d.Errors = append(d.Errors, f.e)
+		}
+	}
+	for _, f := range error2Flags {
+		if s.Error2&f.bit != 0 {
+			d.Errors = append(d.Errors, f.e)
+		}
+	}
+	return d, nil
+}
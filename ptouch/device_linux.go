@@ -0,0 +1,73 @@
+//go:build linux
+
+package ptouch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// ioc mirrors the Linux kernel's _IOC(dir, type, nr, size) macro from
+// asm-generic/ioctl.h, used to build LPIOC_GET_DEVICE_ID(len).
+func ioc(dir, typ, nr, size uintptr) uintptr {
+	const (
+		nrShift   = 0
+		typeShift = 8
+		sizeShift = 16
+		dirShift  = 30
+	)
+	return (dir << dirShift) | (typ << typeShift) | (nr << nrShift) | (size << sizeShift)
+}
+
+const iocRead = 2
+
+// lpIOCGetDeviceID is LPIOC_GET_DEVICE_ID(1024) from linux/lp.h: _IOC(_IOC_READ, 'P', 1, 1024).
+var lpIOCGetDeviceID = ioc(iocRead, 'P', 1, 1024)
+
+// queryDeviceID issues LPIOC_GET_DEVICE_ID against an already-open printer
+// device and parses the resulting IEEE-1284 Device ID string.
+func queryDeviceID(f *os.File) (*DeviceID, error) {
+	buf := make([]byte, 1024)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), lpIOCGetDeviceID, uintptr(unsafe.Pointer(&buf[0])))
+	if errno != 0 {
+		return nil, fmt.Errorf("LPIOC_GET_DEVICE_ID on %s failed: %v", f.Name(), errno)
+	}
+	return parseDeviceID(buf)
+}
+
+// FindDevice scans /dev/usb/lp* for a Brother printer, returning the path of
+// the first one found. This lets callers avoid hardcoding a device path in
+// multi-printer or udev-renumbered environments.
+func FindDevice() (string, error) {
+	paths, err := filepath.Glob("/dev/usb/lp*")
+	if err != nil {
+		return "", fmt.Errorf("unable to glob /dev/usb/lp*: %v", err)
+	}
+	sort.Strings(paths)
+
+	var errs []string
+	for _, p := range paths {
+		f, err := os.OpenFile(p, os.O_RDWR, 0)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p, err))
+			continue
+		}
+		id, err := queryDeviceID(f)
+		f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p, err))
+			continue
+		}
+		if id.MFG != "Brother" {
+			errs = append(errs, fmt.Sprintf("%s: unexpected MFG %q", p, id.MFG))
+			continue
+		}
+		return p, nil
+	}
+	return "", fmt.Errorf("no Brother printer found among %v: %s", paths, strings.Join(errs, "; "))
+}
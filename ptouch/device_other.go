@@ -0,0 +1,20 @@
+//go:build !linux
+
+package ptouch
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// queryDeviceID is only implemented on Linux, where LPIOC_GET_DEVICE_ID is
+// available.
+func queryDeviceID(f *os.File) (*DeviceID, error) {
+	return nil, fmt.Errorf("device ID probing is not supported on %s", runtime.GOOS)
+}
+
+// FindDevice is only implemented on Linux, where /dev/usb/lp* exists.
+func FindDevice() (string, error) {
+	return "", fmt.Errorf("automatic device discovery is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,53 @@
+package ptouch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// DeviceID holds the fields we care about from a printer's IEEE-1284 Device
+// ID string, as returned by LPIOC_GET_DEVICE_ID.
+type DeviceID struct {
+	MFG  string
+	MDL  string
+	CMD  string
+	SERN string
+}
+
+// parseDeviceID decodes a raw IEEE-1284 Device ID buffer: the first two
+// bytes are a big-endian length (including themselves), followed by
+// semicolon-separated "KEY:VALUE;" pairs.
+func parseDeviceID(b []byte) (*DeviceID, error) {
+	if len(b) < 2 {
+		return nil, fmt.Errorf("device ID too short: %d bytes", len(b))
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if n < 2 || n > len(b) {
+		return nil, fmt.Errorf("device ID length %d out of range for %d-byte buffer", n, len(b))
+	}
+	s := string(b[2:n])
+
+	d := &DeviceID{}
+	for _, pair := range strings.Split(s, ";") {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch key {
+		case "MFG", "MANUFACTURER":
+			d.MFG = val
+		case "MDL", "MODEL":
+			d.MDL = val
+		case "CMD", "COMMAND SET":
+			d.CMD = val
+		case "SERN":
+			d.SERN = val
+		}
+	}
+	if d.MFG == "" && d.MDL == "" {
+		return nil, fmt.Errorf("no MFG/MDL found in device ID %q", s)
+	}
+	return d, nil
+}
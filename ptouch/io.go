@@ -0,0 +1,89 @@
+package ptouch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// errTimeout is returned when a printer operation doesn't complete within
+// its configured timeout.
+var errTimeout = errors.New("timed out waiting for printer")
+
+// errInvalidRead is returned when a read off the printer returns a short
+// read that binary.Read can't decode into a rawStatus.
+var errInvalidRead = errors.New("invalid (short) read from printer")
+
+// Timeouts bundles the per-operation deadlines used when talking to the
+// printer. Open sets these to DefaultTimeouts; callers can adjust
+// individual fields on Printer.Timeouts afterwards.
+type Timeouts struct {
+	// StatusRead bounds how long to wait for a single status reply.
+	StatusRead time.Duration
+	// PrintComplete bounds how long to wait for a print job, from the
+	// feed-and-cut command to the printer reporting it's done.
+	PrintComplete time.Duration
+}
+
+// DefaultTimeouts are the Timeouts a freshly-Open'd Printer starts with.
+var DefaultTimeouts = Timeouts{
+	StatusRead:    10 * time.Second,
+	PrintComplete: 30 * time.Second,
+}
+
+// rawStatusResult is one decoded reply (or error) produced by the reader
+// goroutine started in Open.
+type rawStatusResult struct {
+	s   *rawStatus
+	err error
+}
+
+// startReader launches the single long-lived goroutine that reads status
+// replies off the device for the lifetime of p. Using one persistent reader,
+// rather than spawning a new one per call, means a timed-out read's bytes
+// are never handed to the wrong caller: they're simply the next value
+// readRawStatusTimeout receives, since p.statusCh is the only consumer of
+// the device's read side.
+func (p *Printer) startReader() {
+	p.statusCh = make(chan rawStatusResult, 1)
+	go func() {
+		buf := make([]byte, 32)
+		for {
+			n, err := io.ReadFull(p.f, buf)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			if err != nil {
+				p.statusCh <- rawStatusResult{nil, err}
+				return
+			}
+			if n != len(buf) {
+				p.statusCh <- rawStatusResult{nil, errInvalidRead}
+				return
+			}
+			s := &rawStatus{}
+			if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, s); err != nil {
+				p.statusCh <- rawStatusResult{nil, err}
+				return
+			}
+			p.statusCh <- rawStatusResult{s, nil}
+		}
+	}()
+}
+
+// readRawStatusTimeout waits up to timeout for the reader goroutine started
+// by startReader to deliver the next status reply. On timeout, the reply is
+// left unread in p.statusCh (it's buffered, size 1) rather than discarded,
+// so the next call - the next poll, the next status request - picks it up
+// instead of it being silently lost.
+func (p *Printer) readRawStatusTimeout(timeout time.Duration) (*rawStatus, error) {
+	select {
+	case res := <-p.statusCh:
+		return res.s, res.err
+	case <-time.After(timeout):
+		return nil, errTimeout
+	}
+}
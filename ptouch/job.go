@@ -0,0 +1,177 @@
+package ptouch
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// CutMode selects how far the cutter cuts after a page, via ESC i K.
+type CutMode byte
+
+const (
+	// CutFull cuts all the way through the tape, separating the label.
+	CutFull CutMode = 0x08
+	// CutHalf cuts through the label only, leaving the backing paper
+	// intact, so a strip of labels can be peeled individually later.
+	CutHalf CutMode = 0x0C
+)
+
+// ChainMode selects, for a page within a multi-page Job, whether the tape
+// is cut after that specific page or only fed, saving the cut for a later
+// page (typically the job's last).
+type ChainMode int
+
+const (
+	// ChainCutAtEnd feeds-and-continues after this page (command 0x0C):
+	// the tape isn't cut, so the next page prints straight after it.
+	ChainCutAtEnd ChainMode = iota
+	// ChainCutEach feeds-and-cuts after this page (command 0x1A) even
+	// though more pages follow in the same Job.
+	ChainCutEach
+)
+
+// PageOptions controls how a page within a Job is finished. AutoCut, Cut and
+// FeedMM are mode settings applied once for the whole job, from the first
+// page's options (see Job.Submit) — only Chain is read per page.
+type PageOptions struct {
+	// AutoCut enables the printer's auto-cut feature for the job.
+	AutoCut bool
+	// Cut selects full vs half cut, when AutoCut is true.
+	Cut CutMode
+	// FeedMM is the feed amount, in mm, fed out before a cut.
+	FeedMM int
+	// Chain selects whether this page is cut individually or left
+	// uncut for a later page in the same Job to cut. The job's last page
+	// is always fed-and-cut, regardless of Chain, so the final label is
+	// released.
+	Chain ChainMode
+}
+
+// DefaultPageOptions auto-cuts fully after every page with a small feed,
+// matching the printer's previous (pre-Job) fixed behaviour.
+var DefaultPageOptions = PageOptions{
+	AutoCut: true,
+	Cut:     CutFull,
+	FeedMM:  4,
+	Chain:   ChainCutEach,
+}
+
+type jobPage struct {
+	lines [][]byte
+	opts  PageOptions
+}
+
+// Job batches one or more pages to be printed as a single chained print:
+// mode-setting commands are sent once at the start of the job, and pages
+// whose ChainMode is ChainCutAtEnd are fed-and-continued (0x0C) rather than
+// fed-and-cut (0x1A), so no tape is wasted between them. This matches
+// Brother's chain-printing convention.
+type Job struct {
+	p     *Printer
+	pages []jobPage
+}
+
+// NewJob starts a new print job on p.
+func (p *Printer) NewJob() *Job {
+	return &Job{p: p}
+}
+
+// AddPage queues img (already rotated to print orientation, e.g. via
+// LeftRotate) to be printed with the given options. Of these, only opts.Chain
+// is specific to this page; AutoCut, Cut and FeedMM only matter on the job's
+// first page, since they configure the job as a whole (see PageOptions).
+func (j *Job) AddPage(img image.Image, opts PageOptions) error {
+	lines, err := j.p.PackRasterLines(img)
+	if err != nil {
+		return fmt.Errorf("error packing page %d: %v", len(j.pages), err)
+	}
+	j.pages = append(j.pages, jobPage{lines: lines, opts: opts})
+	return nil
+}
+
+// Submit sends all queued pages to the printer as a single chained job,
+// waiting for each page to complete before sending the next. The mode set
+// by the first page's options (auto-cut, cut depth, feed amount) applies
+// to the whole job. Submit locks the underlying Printer for its duration,
+// so concurrent Submit calls against the same Printer (e.g. from concurrent
+// HTTP requests) are serialized rather than interleaving on the wire.
+func (j *Job) Submit() error {
+	if len(j.pages) == 0 {
+		return fmt.Errorf("job has no pages")
+	}
+	j.p.ioMu.Lock()
+	defer j.p.ioMu.Unlock()
+
+	if err := j.p.setJobMode(j.pages[0].opts); err != nil {
+		return fmt.Errorf("error setting job mode: %v", err)
+	}
+	for i, pg := range j.pages {
+		last := i == len(j.pages)-1
+		cutNow := last || pg.opts.Chain == ChainCutEach
+		if err := j.p.sendPage(pg.lines, cutNow); err != nil {
+			return fmt.Errorf("error printing page %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// setJobMode emits the mode-setting ESC commands for a job: auto-cut
+// enable/depth (ESC i M, ESC i K) and feed margin (ESC i d).
+func (p *Printer) setJobMode(opts PageOptions) error {
+	// QL-series printers' cutter units don't support a half-cut (label only,
+	// backing paper intact): that's a PT-series feature.
+	if opts.Cut == CutHalf && p.Model.IsQL() {
+		return fmt.Errorf("half-cut is not supported on QL-series printers (model %s)", p.Model)
+	}
+
+	// 0x08 was always set by the pre-Job fixed command and must stay set
+	// regardless of AutoCut; only the 0x40 auto-cut-enable bit is conditional.
+	modeFlag := byte(0x08)
+	if opts.AutoCut {
+		modeFlag |= 0x40
+	}
+	escIM := []byte{0x1B, 'i', 'M', modeFlag}
+	escIK := []byte{0x1B, 'i', 'K', byte(opts.Cut)}
+	escId := []byte{0x1B, 'i', 'd', byte(opts.FeedMM), byte(opts.FeedMM >> 8)}
+
+	if err := p.write(escIM); err != nil {
+		return fmt.Errorf("unable to set cut mode: %v", err)
+	}
+	if err := p.write(escIK); err != nil {
+		return fmt.Errorf("unable to set cut depth: %v", err)
+	}
+	if err := p.write(escId); err != nil {
+		return fmt.Errorf("unable to set feed margin: %v", err)
+	}
+	return nil
+}
+
+// sendPage writes one page's raster lines, then either feeds-and-cuts
+// (0x1A) if cut is true, or feeds-and-continues (0x0C) so the next page in
+// the chain prints immediately after, then waits for the printer to
+// confirm the page is done.
+func (p *Printer) sendPage(lines [][]byte, cut bool) error {
+	for _, line := range lines {
+		if err := p.write(line); err != nil {
+			return fmt.Errorf("error writing raster line: %v", err)
+		}
+	}
+	end := byte(0x0C)
+	if cut {
+		end = 0x1A
+	}
+	if err := p.write([]byte{end}); err != nil {
+		return fmt.Errorf("error ending page: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeouts.PrintComplete)
+	defer cancel()
+	_, err := p.WaitForStatus(ctx, func(s *Status) bool {
+		return s.StatusType == StatusTypePrintingCompleted
+	})
+	if err != nil {
+		return fmt.Errorf("error waiting for page to complete: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package ptouch
+
+import "strings"
+
+// Model identifies the specific Brother printer a Printer is talking to.
+// Protocol details (raster width, available commands) vary enough between
+// the PT- and QL- series that callers sometimes need to know which they have.
+type Model int
+
+// Known models, keyed off the "MDL" field of the printer's IEEE-1284 device ID.
+const (
+	ModelUnknown Model = iota
+	ModelPT1230PC
+	ModelPT2430PC
+	ModelPTP700
+	ModelPTP750W
+	ModelQL500
+	ModelQL550
+	ModelQL700
+	ModelQL710W
+	ModelQL720NW
+)
+
+var modelsByMDL = map[string]Model{
+	"PT-1230PC": ModelPT1230PC,
+	"PT-2430PC": ModelPT2430PC,
+	"PT-P700":   ModelPTP700,
+	"PT-P750W":  ModelPTP750W,
+	"QL-500":    ModelQL500,
+	"QL-550":    ModelQL550,
+	"QL-700":    ModelQL700,
+	"QL-710W":   ModelQL710W,
+	"QL-720NW":  ModelQL720NW,
+}
+
+// String returns the model's MDL name, e.g. "PT-P750W", or "unknown" if the
+// model wasn't recognised.
+func (m Model) String() string {
+	for mdl, mm := range modelsByMDL {
+		if mm == m {
+			return mdl
+		}
+	}
+	return "unknown"
+}
+
+// modelFromMDL maps the MDL field of a parsed device ID to a Model. Brother's
+// MDL strings sometimes carry a trailing variant suffix (e.g. "PT-P750W;"
+// already stripped by the caller, but also occasionally extra whitespace), so
+// this trims before comparing.
+func modelFromMDL(mdl string) Model {
+	if m, ok := modelsByMDL[strings.TrimSpace(mdl)]; ok {
+		return m
+	}
+	return ModelUnknown
+}
+
+// IsQL reports whether the model is one of the QL label-printer series, as
+// opposed to the PT handheld/desktop series.
+func (m Model) IsQL() bool {
+	switch m {
+	case ModelQL500, ModelQL550, ModelQL700, ModelQL710W, ModelQL720NW:
+		return true
+	}
+	return false
+}
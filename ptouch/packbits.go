@@ -0,0 +1,42 @@
+package ptouch
+
+// packBits compresses a single raster row using the PackBits/TIFF RLE
+// scheme the Brother protocol expects: a sequence of control-byte-plus-data
+// runs, where a control byte n in [0,127] means "copy the next n+1 literal
+// bytes" and n in [-127,-1] (stored as the unsigned byte 256+n) means
+// "repeat the next byte 1-n times". Rows that don't compress well (e.g. a
+// dense dithered image) fall back to chains of literal runs, which can
+// never expand the data by more than the one control byte per 128 bytes.
+func packBits(row []byte) []byte {
+	var out []byte
+	n := len(row)
+	for i := 0; i < n; {
+		runLen := runLengthAt(row, i)
+		if runLen >= 2 {
+			out = append(out, byte(256-(runLen-1)), row[i])
+			i += runLen
+			continue
+		}
+		start := i
+		i++
+		for i < n && i-start < 128 {
+			if runLengthAt(row, i) >= 2 {
+				break
+			}
+			i++
+		}
+		out = append(out, byte(i-start-1))
+		out = append(out, row[start:i]...)
+	}
+	return out
+}
+
+// runLengthAt returns how many consecutive bytes starting at i are equal to
+// row[i], capped at 128 (the maximum a single PackBits run can encode).
+func runLengthAt(row []byte, i int) int {
+	n := 1
+	for i+n < len(row) && row[i+n] == row[i] && n < 128 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,211 @@
+// Package ptouch talks to Brother P-touch/QL label printers over their raw
+// USB printer-class device (e.g. /dev/usb/lp0), using the protocol
+// documented at http://www.undocprint.org/formats/page_description_languages/brother_p-touch
+// and in ptprint.rb, which this package was originally based on.
+package ptouch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// Printer represents an open connection to a single Brother label printer.
+type Printer struct {
+	f           *os.File
+	Model       Model
+	MediaWidth  int // in mm, as reported by the printer's status reply
+	compression CompressionMode
+	// statusCh delivers status replies from the single long-lived reader
+	// goroutine started in Open; see readRawStatusTimeout.
+	statusCh chan rawStatusResult
+	// ioMu serializes whole write-then-read exchanges with the printer:
+	// Job.Submit, Status and Initialize each span multiple writes and/or
+	// status reads, and p is typically shared across concurrent HTTP
+	// handlers, so two exchanges in flight at once would otherwise
+	// interleave commands and steal each other's status replies off the
+	// shared statusCh.
+	ioMu sync.Mutex
+	// Timeouts controls how long operations wait for the printer to
+	// respond. Open sets this to DefaultTimeouts; adjust individual fields
+	// as needed.
+	Timeouts Timeouts
+}
+
+// Open opens the printer device at devicePath. Callers that don't know the
+// path can use FindDevice to locate one automatically. The printer is not
+// usable until Initialize has been called.
+func Open(devicePath string) (*Printer, error) {
+	f, err := os.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open printer %s: %v", devicePath, err)
+	}
+	p := &Printer{f: f, Timeouts: DefaultTimeouts}
+	p.startReader()
+
+	// Device ID probing tells us the exact model, which lets callers (and
+	// Initialize) gate protocol behaviour per-model. It's not available on
+	// every platform/device, so a failure here isn't fatal.
+	if id, err := queryDeviceID(f); err == nil {
+		p.Model = modelFromMDL(id.MDL)
+	}
+	return p, nil
+}
+
+// Close closes the underlying device.
+func (p *Printer) Close() error {
+	return p.f.Close()
+}
+
+func (p *Printer) write(b []byte) error {
+	n, err := p.f.Write(b)
+	if n != len(b) || err != nil {
+		return fmt.Errorf("failed writing, wrote %d bytes, err %v", n, err)
+	}
+	return nil
+}
+
+// Initialize resets the printer and reads its model and media via a status
+// request. On success, p.Model and p.MediaWidth are populated. Cut/feed
+// mode is no longer set here: it's emitted per-Job (see Job.Submit), so
+// different jobs can use different cut behaviour. Call SetCompression
+// afterwards to switch to RLE-compressed raster lines.
+func (p *Printer) Initialize() error {
+	p.ioMu.Lock()
+	defer p.ioMu.Unlock()
+
+	start := make([]byte, 200)
+	reset := []byte{0x1B, '@'}
+	getStatus := []byte{0x1B, 'i', 'S'}
+
+	if err := p.write(start); err != nil {
+		return fmt.Errorf("unable to start communication: %v", err)
+	}
+	if err := p.write(reset); err != nil {
+		return fmt.Errorf("unable to reset printer: %v", err)
+	}
+	if err := p.write(getStatus); err != nil {
+		return fmt.Errorf("unable to ask printer for status: %v", err)
+	}
+
+	s, err := p.readStatus()
+	if err != nil {
+		return fmt.Errorf("error reading printer status: %v", err)
+	}
+	if len(s.Errors) > 0 {
+		return fmt.Errorf("printer reports error: %v", s.Errors)
+	}
+	p.MediaWidth = s.MediaWidthMM
+
+	if err := p.SetCompression(CompressionNone); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Status requests a fresh status reply from the printer and decodes it.
+func (p *Printer) Status() (*Status, error) {
+	p.ioMu.Lock()
+	defer p.ioMu.Unlock()
+
+	getStatus := []byte{0x1B, 'i', 'S'}
+	if err := p.write(getStatus); err != nil {
+		return nil, fmt.Errorf("unable to ask printer for status: %v", err)
+	}
+	return p.readStatus()
+}
+
+// readStatus reads one raw status reply off the wire, within p.Timeouts.StatusRead,
+// and decodes it.
+func (p *Printer) readStatus() (*Status, error) {
+	raw, err := p.readRawStatusTimeout(p.Timeouts.StatusRead)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStatus(raw, p.Model)
+}
+
+// WaitForStatus polls the printer, via unsolicited status replies it sends
+// during/after a print (or, if none arrive, via explicit Status requests),
+// until pred returns true for a decoded status or ctx is done. Phase-change
+// and notification replies that don't satisfy pred are logged and waited
+// past, rather than treated as errors. It returns the status that satisfied
+// pred, or the ctx error on timeout/cancellation.
+func (p *Printer) WaitForStatus(ctx context.Context, pred func(*Status) bool) (*Status, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		s, err := p.readStatus()
+		if err != nil {
+			return nil, fmt.Errorf("error reading printer status: %v", err)
+		}
+		if s.StatusType == StatusTypeError {
+			return s, fmt.Errorf("printer reports error: %v", s.Errors)
+		}
+		if pred(s) {
+			return s, nil
+		}
+		switch s.StatusType {
+		case StatusTypePhaseChange:
+			log.Printf("printer phase changed: %s (phase number %d)", s.Phase, s.PhaseNumber)
+		case StatusTypeNotification:
+			log.Printf("printer notification: %s", s.Notification)
+		}
+	}
+}
+
+// frameWidthPixels returns the printer's raster head width, in pixels: the
+// number of bits PackRasterLines must pack into every line, fixed per model
+// series regardless of what media is loaded. The printer always wants a
+// full frame of this many bits; for narrower tape, it only prints the
+// middle of it (see MediaWidthPixels, used for centering within the frame).
+func (p *Printer) frameWidthPixels() int {
+	if p.Model.IsQL() {
+		return 720
+	}
+	return 128
+}
+
+// MediaWidthPixels returns the printable width, in pixels, of the
+// currently-loaded media. This is narrower than (or equal to) the fixed
+// frame returned by frameWidthPixels, and is used only to center image data
+// within that frame, not to size it.
+func (p *Printer) MediaWidthPixels() int {
+	if p.Model.IsQL() {
+		switch p.MediaWidth {
+		case 12:
+			return 106
+		case 29:
+			return 306
+		case 38:
+			return 413
+		case 50:
+			return 554
+		case 62:
+			return 696
+		default:
+			return 720
+		}
+	}
+	switch p.MediaWidth {
+	case 9:
+		return 64
+	default:
+		return 128
+	}
+}
+
+// PrintRaster sends pre-packed raster lines (each a full "G"-command frame,
+// as built by the caller) to the printer as a single-page Job with
+// DefaultPageOptions, then blocks until the printer confirms it's done.
+// Callers that need multiple pages, or non-default cut/feed behaviour,
+// should use NewJob directly.
+func (p *Printer) PrintRaster(lines [][]byte) error {
+	j := &Job{p: p, pages: []jobPage{{lines: lines, opts: DefaultPageOptions}}}
+	return j.Submit()
+}
@@ -0,0 +1,97 @@
+package ptouch
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// leftRotated is a lazy view of img rotated 90 degrees counter-clockwise:
+// what was img's top-left pixel ends up at the bottom-left. The printer's
+// raster head scans across the tape's width one print-direction line at a
+// time, so text composed normally (reading left-to-right) needs this
+// rotation before it can be packed into raster lines. Built the same way
+// sklad's leftRotate swaps X/Y on At, this avoids allocating a rotated copy
+// of the image.
+type leftRotated struct {
+	img image.Image
+}
+
+// LeftRotate wraps img so that reads see it rotated 90 degrees
+// counter-clockwise, without copying pixel data.
+func LeftRotate(img image.Image) image.Image {
+	return &leftRotated{img}
+}
+
+func (r *leftRotated) ColorModel() color.Model {
+	return r.img.ColorModel()
+}
+
+func (r *leftRotated) Bounds() image.Rectangle {
+	b := r.img.Bounds()
+	return image.Rect(0, 0, b.Dy(), b.Dx())
+}
+
+func (r *leftRotated) At(x, y int) color.Color {
+	b := r.img.Bounds()
+	return r.img.At(b.Min.X+y, b.Max.Y-1-x)
+}
+
+// rowBits renders one row of img (at offset y within its bounds) into a
+// packed 1-bit-per-pixel bitmap, rowBytes long (the printer's fixed raster
+// frame width), with the image horizontally centered within activeWidth
+// pixels - the currently-loaded media's printable width, itself centered
+// within the frame.
+func rowBits(img image.Image, y, rowBytes, activeWidth int) []byte {
+	b := img.Bounds()
+	frameWidth := rowBytes * 8
+	padLeft := (frameWidth-activeWidth)/2 + (activeWidth-b.Dx())/2
+
+	row := make([]byte, rowBytes)
+	for x := 0; x < b.Dx(); x++ {
+		pr, _, _, _ := img.At(b.Min.X+x, y).RGBA()
+		if pr == 0 {
+			bit := padLeft + x
+			row[bit/8] |= 128 >> uint(bit%8)
+		}
+	}
+	return row
+}
+
+// PackRasterLines packs img (already rotated to print orientation, e.g. via
+// LeftRotate) into raster lines, each a full "G" command frame ready for
+// PrintRaster. Each line is the printer's fixed per-model frame width (see
+// frameWidthPixels), even on narrower tape; pixels are centered within the
+// currently-loaded media's printable width (MediaWidthPixels), which is
+// itself centered within that frame. Framing depends on the printer's
+// current compression mode (see SetCompression): uncompressed lines are
+// fixed-length, RLE lines are PackBits-compressed with their actual length
+// in the frame header.
+func (p *Printer) PackRasterLines(img image.Image) ([][]byte, error) {
+	active := p.MediaWidthPixels()
+	b := img.Bounds()
+	if b.Dx() > active {
+		return nil, fmt.Errorf("image width %d exceeds printable width %d", b.Dx(), active)
+	}
+	rowBytes := p.frameWidthPixels() / 8
+
+	lines := make([][]byte, 0, b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := rowBits(img, y, rowBytes, active)
+
+		var data []byte
+		if p.compression == CompressionRLE {
+			data = packBits(row)
+		} else {
+			data = row
+		}
+
+		line := make([]byte, 3+len(data))
+		line[0] = 'G'
+		line[1] = byte(len(data))
+		line[2] = byte(len(data) >> 8)
+		copy(line[3:], data)
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -0,0 +1,66 @@
+package ptouch
+
+import "fmt"
+
+// rawStatus is the wire format of the 32-byte status reply from the printer,
+// mostly zeroes. The fields prefixed "res" are marked "reserved" in the
+// documentation, although some of them have actual meanings.
+type rawStatus struct {
+	PrintHeadMark byte
+	Size          byte
+	ResFixed1     byte
+	ResFixed2     byte
+	ResHWVersion  byte
+	ResFixed3     byte
+	ResZero0      byte
+	ResZero1      byte
+	Error1        byte
+	Error2        byte
+	MediaWidth    byte
+	Mediatype     byte
+	ResZero2      byte
+	ResZero3      byte
+	ResZero4      byte
+	ResZero5      byte
+	ResZero6      byte
+	MediaLength   byte
+	StatusType    byte
+	PhaseType     byte
+	PhaseHigh     byte
+	PhaseLow      byte
+	NotifNum      byte
+	ResZero7      byte
+	ResZero8      byte
+	ResZero9      byte
+	ResZeroA      byte
+	ResZeroB      byte
+	ResZeroC      byte
+	ResZeroD      byte
+	ResZeroE      byte
+	ResZeroF      byte
+}
+
+// checkFixedFields verifies the parts of a status reply that should never
+// vary between printers/firmwares, as a sanity check that we're actually
+// talking to a printer and in sync with its reply stream.
+func checkFixedFields(s *rawStatus) error {
+	if s.PrintHeadMark != 0x80 {
+		return fmt.Errorf("wanted PrintHeadMark 0x80, got 0x%02X", s.PrintHeadMark)
+	}
+	if s.Size != 32 {
+		return fmt.Errorf("wanted Size 32, got %d", s.Size)
+	}
+	if s.ResFixed1 != 0x42 {
+		return fmt.Errorf("wanted Fixed1 0x42, got 0x%02X", s.ResFixed1)
+	}
+	if s.ResFixed2 != 0x30 {
+		return fmt.Errorf("wanted Fixed2 0x30, got 0x%02X", s.ResFixed2)
+	}
+	if s.ResHWVersion != 0x5a {
+		return fmt.Errorf("wanted ResHWVersion 0x5a, got 0x%02X", s.ResHWVersion)
+	}
+	if s.ResFixed3 != 0x30 {
+		return fmt.Errorf("wanted Fixed3 0x30, got 0x%02X", s.ResFixed3)
+	}
+	return nil
+}
@@ -2,329 +2,174 @@ package main
 
 import "bytes"
 import "encoding/base64"
-import "encoding/binary"
-import "errors"
 import "fmt"
 import "html"
 import "image"
-import _ "image/png"
-import "io"
+import "image/png"
 import "log"
 import "net/http"
-import "os"
-import "os/exec"
 import "strings"
-import "time"
 
-// Much of this was based on ptprint.rb, linked from
-// http://www.undocprint.org/formats/page_description_languages/brother_p-touch
-// as well as the other docs linked from that page
+import "github.com/Jon-Bright/ptprint/ptouch"
+import "github.com/Jon-Bright/ptprint/render"
 
-func write(f *os.File, b []byte) error {
-	n, err := f.Write(b)
-	if n != len(b) || err != nil {
-		return fmt.Errorf("failed writing, wrote %d bytes, err %v", n, err)
-	}
-	// If I don't do this, I see regular EOFs when trying to read e.g. the printer's
-	// status reply.  I'm guessing it doesn't have the world's fastest processor.
-	// The actual delay here is plucked out of thin air, but is not painfully long
-	// but long enough to (apparently) work.
-	time.Sleep(time.Duration(len(b)) * time.Millisecond)
-	return nil
-}
-
-// Status is the somewhat insane mostly-zeroes status reply from the printer.
-// All of the fields prefixed "res" are marked "reserved" in the documentation,
-// although some of them have actual meanings.
-type Status struct {
-	PrintHeadMark byte
-	Size          byte
-	ResFixed1     byte
-	ResFixed2     byte
-	ResHWVersion  byte
-	ResFixed3     byte
-	ResZero0      byte
-	ResZero1      byte
-	Error1        byte
-	Error2        byte
-	MediaWidth    byte
-	Mediatype     byte
-	ResZero2      byte
-	ResZero3      byte
-	ResZero4      byte
-	ResZero5      byte
-	ResZero6      byte
-	MediaLength   byte
-	StatusType    byte
-	PhaseType     byte
-	PhaseHigh     byte
-	PhaseLow      byte
-	NotifNum      byte
-	ResZero7      byte
-	ResZero8      byte
-	ResZero9      byte
-	ResZeroA      byte
-	ResZeroB      byte
-	ResZeroC      byte
-	ResZeroD      byte
-	ResZeroE      byte
-	ResZeroF      byte
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "<html>\n<head>\n<title>Label printer</title>\n<body>\n<form action='/preview' method='post'>Text to print (separate labels with a blank line):<textarea name='text' rows='8' cols='50'></textarea>\n<input type='submit' value='Preview'>\n")
 }
 
-func readStatus(f *os.File) (*Status, error) {
-	s := Status{}
-	var err error
-	// With a freshly-started printer, this often takes a couple of retries
-	for i := 0; i < 10; i++ {
-		err = binary.Read(f, binary.LittleEndian, &s)
-		if err == nil || err != io.EOF {
-			break
+// pagesFromText splits a form submission's text into one string per label:
+// blank lines separate labels, so a single HTTP request can submit several
+// pages to be printed as one chained job.
+func pagesFromText(text string) []string {
+	// Browsers normalize <textarea> line endings to CRLF on submission, so a
+	// blank line typed by the user arrives as "\r\n\r\n", not "\n\n".
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	var pages []string
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			pages = append(pages, p)
 		}
-		log.Printf("EOF reading status, try %d", i)
-		time.Sleep(time.Second)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("Could not read status: %v", err)
-	}
-	return &s, nil
-}
-
-func checkStatus(s *Status) error {
-	if s.PrintHeadMark != 0x80 {
-		return fmt.Errorf("wanted PrintHeadMark 0x80, got 0x%02X", s.PrintHeadMark)
-	}
-	if s.Size != 32 {
-		return fmt.Errorf("wanted Size 32, got %d", s.Size)
-	}
-	if s.ResFixed1 != 0x42 {
-		return fmt.Errorf("wanted Fixed1 0x42, got 0x%02X", s.ResFixed1)
-	}
-	if s.ResFixed2 != 0x30 {
-		return fmt.Errorf("wanted Fixed2 0x30, got 0x%02X", s.ResFixed2)
-	}
-	if s.ResHWVersion != 0x5a {
-		return fmt.Errorf("wanted ResHWVersion 0x5a, got 0x%02X", s.ResHWVersion)
-	}
-	if s.ResFixed3 != 0x30 {
-		return fmt.Errorf("wanted Fixed3 0x30, got 0x%02X", s.ResFixed3)
-	}
-	if (s.Error1 & 0x01) != 0x00 {
-		return errors.New("no print media")
-	}
-	if (s.Error1 & 0x02) != 0x00 {
-		return errors.New("end of print media")
-	}
-	if (s.Error1 & 0x04) != 0x00 {
-		return errors.New("tape cutter jam")
-	}
-	if s.Error1 != 0x00 {
-		return fmt.Errorf("unknown Error1 %02X", s.Error1)
-	}
-	if (s.Error2 & 0x04) != 0x00 {
-		return errors.New("transmission error")
-	}
-	if (s.Error2 & 0x10) != 0x00 {
-		return errors.New("cover open")
-	}
-	if (s.Error2 & 0x40) != 0x00 {
-		return errors.New("cannot feed print media")
-	}
-	if s.Error2 != 0x00 {
-		return fmt.Errorf("unknown ErrorInfo2 %02X", s.Error2)
 	}
-	return nil
+	return pages
 }
 
-func initPrinter(devicePath string) (*os.File, int, error) {
-	f, err := os.OpenFile(devicePath, os.O_RDWR, 0)
-	if err != nil {
-		return nil, 0, fmt.Errorf("unable to open printer %s: %v", devicePath, err)
-	}
-
-	start := make([]byte, 200)
-	reset := []byte{0x1B, '@'}
-	getStatus := []byte{0x1B, 'i', 'S'}
-	setAutoCut := []byte{0x1B, 'i', 'M', 0x48} // Auto cut, small feed amount
-	setFullCut := []byte{0x1B, 'i', 'K', 0x08} // Cut all the way through after every print
-	setCompression := []byte{'M', 0x02}        // Use RLE compression (which we won't actually do, but whatever)
-
-	err = write(f, start)
-	if err != nil {
-		return nil, 0, fmt.Errorf("unable to start communication: %v", err)
-	}
-
-	err = write(f, reset)
-	if err != nil {
-		return nil, 0, fmt.Errorf("unable to reset printer: %v", err)
-	}
-
-	err = write(f, getStatus)
-	if err != nil {
-		return nil, 0, fmt.Errorf("unable to ask printer for status: %v", err)
-	}
-
-	s, err := readStatus(f)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error reading printer status: %v", err)
-	}
-
-	err = checkStatus(s)
-	if err != nil {
-		return nil, 0, fmt.Errorf("printer reports error: %v", err)
-	}
-
-	err = write(f, setAutoCut)
-	if err != nil {
-		return nil, 0, fmt.Errorf("unable to set auto-cut: %v", err)
-	}
-
-	err = write(f, setFullCut)
-	if err != nil {
-		return nil, 0, fmt.Errorf("unable to set full cut: %v", err)
-	}
-
-	err = write(f, setCompression)
-	if err != nil {
-		return nil, 0, fmt.Errorf("unable to set compression: %v", err)
-	}
-	return f, int(s.MediaWidth), nil
+// renderLabel builds the label image for text, sized to fit the printer's
+// media width. Both previewHandler and printHandler use this, so what's
+// previewed is exactly what gets printed (printHandler additionally rotates
+// it for the print head).
+func renderLabel(p *ptouch.Printer, text string) (image.Image, error) {
+	heightPx := p.MediaWidthPixels()
+	opts := render.Options{
+		HeightPx:   heightPx,
+		FontSizePt: float64(heightPx) / 2,
+		Align:      render.AlignCenter,
+	}
+	return render.Render(text, opts)
 }
 
-func mediaWidthToPixels(w int) int {
-	switch w {
-	case 9:
-		return 64
-	default:
-		return 128
+func imageToPNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("unable to encode PNG: %v", err)
 	}
-}
-
-func rootHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "<html>\n<head>\n<title>Label printer</title>\n<body>\n<form action='/preview' method='post'>Text to print:<textarea name='text' rows='4' cols='50'></textarea>\n<input type='submit' value='Preview'>\n")
+	return buf.Bytes(), nil
 }
 
 type previewHandler struct {
-	mediaWidth int
+	printer *ptouch.Printer
 }
 
 func (h *previewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	text := r.FormValue("text")
-
-	s := fmt.Sprintf("x%d", mediaWidthToPixels(h.mediaWidth))
-	c := exec.Command("convert", "+antialias", "-background", "white", "-fill", "black", "-size", s, "-gravity", "South", "label:"+text, "png:-")
-	log.Printf("Preview running command '%v'", c)
-	png, err := c.Output()
-	if err != nil {
-		w.WriteHeader(502)
-		fmt.Fprintf(w, "Error generating preview: %v", err)
+	pages := pagesFromText(text)
+	if len(pages) == 0 {
+		w.WriteHeader(400)
+		fmt.Fprintf(w, "No text to print")
 		return
 	}
 
-	htmltext := html.EscapeString(text)
-	htmltext = strings.Replace(htmltext, "\n", "<br />", -1)
 	fmt.Fprintf(w, "<html>\n<head>\n<title>Label preview</title>\n<body>\n<form action='/print' method='post'><input type='hidden' name='text' value='%s'>\n", html.EscapeString(text))
-	fmt.Fprintf(w, "<img border=1 alt='%s' src='data:image/png;base64,", htmltext)
-	fmt.Fprintf(w, "%s", base64.StdEncoding.EncodeToString(png))
-	fmt.Fprintf(w, "' />\n")
-	fmt.Fprintf(w, "<p>%s</p><input type='submit' value='Print'>\n", htmltext)
+	for _, page := range pages {
+		img, err := renderLabel(h.printer, page)
+		if err != nil {
+			w.WriteHeader(502)
+			fmt.Fprintf(w, "Error rendering preview: %v", err)
+			return
+		}
+		pngBytes, err := imageToPNG(img)
+		if err != nil {
+			w.WriteHeader(502)
+			fmt.Fprintf(w, "Error encoding preview: %v", err)
+			return
+		}
+
+		htmltext := html.EscapeString(page)
+		htmltext = strings.Replace(htmltext, "\n", "<br />", -1)
+		fmt.Fprintf(w, "<img border=1 alt='%s' src='data:image/png;base64,", htmltext)
+		fmt.Fprintf(w, "%s", base64.StdEncoding.EncodeToString(pngBytes))
+		fmt.Fprintf(w, "' />\n")
+		fmt.Fprintf(w, "<p>%s</p>\n", htmltext)
+	}
+	fmt.Fprintf(w, "<input type='submit' value='Print'>\n")
 }
 
 type printHandler struct {
-	printer    *os.File
-	mediaWidth int
+	printer *ptouch.Printer
 }
 
 func (h *printHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	text := r.FormValue("text")
-
-	s := fmt.Sprintf("x%d", mediaWidthToPixels(h.mediaWidth))
-	c := exec.Command("convert", "+antialias", "-background", "white", "-fill", "black", "-size", s, "-gravity", "South", "-rotate", "-90", "label:"+text, "png:-")
-	log.Printf("Print running command '%v'", c)
-	png, err := c.Output()
-	if err != nil {
-		w.WriteHeader(502)
-		fmt.Fprintf(w, "Error generating print data: %v", err)
-		return
-	}
-	pngr := bytes.NewReader(png)
-
-	pngc, ifmt, err := image.DecodeConfig(pngr)
-	if err != nil {
-		w.WriteHeader(502)
-		fmt.Fprintf(w, "Error decoding print PNG config: %v", err)
-		return
-	}
-
-	_, err = pngr.Seek(0, 0)
-	if err != nil {
-		w.WriteHeader(502)
-		fmt.Fprintf(w, "Error seeking print PNG: %v", err)
-		return
-	}
-
-	pngi, ifmt, err := image.Decode(pngr)
-	if err != nil {
-		w.WriteHeader(502)
-		fmt.Fprintf(w, "Error decoding print PNG: %v", err)
+	pages := pagesFromText(text)
+	if len(pages) == 0 {
+		w.WriteHeader(400)
+		fmt.Fprintf(w, "No text to print")
 		return
 	}
-	if ifmt != "png" {
-		w.WriteHeader(502)
-		fmt.Fprintf(w, "Error with print PNG, want format 'png', got '%s'", ifmt)
-		return
-	}
-	log.Printf("Read print data in format '%s', width %d, height %d", ifmt, pngc.Width, pngc.Height)
 
-	padLeft := (128 - pngc.Width) / 2
-	padRight := 128 - (pngc.Width + padLeft)
-	for y := pngc.Height - 1; y >= 0; y-- {
-		line := make([]byte, 128/8+4) // The printer always wants 128 pixels of data, but for narrow band, only prints the middle bit
-		line[0] = 'G'
-		line[1] = 0x11
-		line[2] = 0x00
-		line[3] = 0x0f
-		lc := 4
-		for x := 0; x < padLeft; x += 8 {
-			line[lc] = byte(0)
-			lc++
+	job := h.printer.NewJob()
+	var previews [][]byte
+	for _, page := range pages {
+		img, err := renderLabel(h.printer, page)
+		if err != nil {
+			w.WriteHeader(502)
+			fmt.Fprintf(w, "Error rendering print data: %v", err)
+			return
 		}
-		for x := 0; x < pngc.Width; x += 8 {
-			by := uint32(0)
-			for b := 0; b < 8; b++ {
-				pr, _, _, _ := pngi.At(x+b, y).RGBA()
-				if pr == 0 {
-					by = by | (128 >> uint(b))
-				}
-			}
-			line[lc] = byte(by)
-			lc++
+		preview, err := imageToPNG(img)
+		if err != nil {
+			w.WriteHeader(502)
+			fmt.Fprintf(w, "Error encoding print data: %v", err)
+			return
 		}
-		for x := 0; x < padRight; x += 8 {
-			line[lc] = byte(0)
-			lc++
+		previews = append(previews, preview)
+
+		opts := ptouch.DefaultPageOptions
+		opts.Chain = ptouch.ChainCutAtEnd
+		if err := job.AddPage(ptouch.LeftRotate(img), opts); err != nil {
+			w.WriteHeader(502)
+			fmt.Fprintf(w, "Error packing print data: %v", err)
+			return
 		}
-		write(h.printer, line)
 	}
-	endPrint := []byte{0x1a}
-	write(h.printer, endPrint)
+	// All pages share one chained job, so tape is only cut after the last
+	// one: ChainCutAtEnd above keeps earlier pages uncut, and Job.Submit
+	// force-cuts the final page regardless.
+	if err := job.Submit(); err != nil {
+		w.WriteHeader(502)
+		fmt.Fprintf(w, "Error printing: %v", err)
+		return
+	}
 
-	htmltext := html.EscapeString(text)
-	htmltext = strings.Replace(htmltext, "\n", "<br />", -1)
 	fmt.Fprintf(w, "<html>\n<head>\n<title>Label print</title>\n<body>\n")
-	fmt.Fprintf(w, "<img border=1 alt='%s' src='data:image/png;base64,", htmltext)
-	fmt.Fprintf(w, "%s", base64.StdEncoding.EncodeToString(png))
-	fmt.Fprintf(w, "' />\n")
-	fmt.Fprintf(w, "<p>%s</p>\n", htmltext)
+	for i, page := range pages {
+		htmltext := html.EscapeString(page)
+		htmltext = strings.Replace(htmltext, "\n", "<br />", -1)
+		fmt.Fprintf(w, "<img border=1 alt='%s' src='data:image/png;base64,", htmltext)
+		fmt.Fprintf(w, "%s", base64.StdEncoding.EncodeToString(previews[i]))
+		fmt.Fprintf(w, "' />\n")
+		fmt.Fprintf(w, "<p>%s</p>\n", htmltext)
+	}
 }
 
 func main() {
-	f, mediaWidth, err := initPrinter("/dev/usb/lp1")
+	devicePath, err := ptouch.FindDevice()
+	if err != nil {
+		log.Fatalf("Could not find printer: %v", err)
+	}
+	p, err := ptouch.Open(devicePath)
 	if err != nil {
+		log.Fatalf("Could not open printer %s: %v", devicePath, err)
+	}
+	if err := p.Initialize(); err != nil {
 		log.Fatalf("Could not initialize printer: %v", err)
 	}
-	log.Printf("Printer initialized successfully.  Media width is %dmm.\n", mediaWidth)
+	if err := p.SetCompression(ptouch.CompressionRLE); err != nil {
+		log.Fatalf("Could not enable compression: %v", err)
+	}
+	log.Printf("Printer %s (%s) initialized successfully.  Media width is %dmm.\n", devicePath, p.Model, p.MediaWidth)
 	http.HandleFunc("/", rootHandler)
-	http.Handle("/preview", &previewHandler{mediaWidth})
-	http.Handle("/print", &printHandler{f, mediaWidth})
+	http.Handle("/preview", &previewHandler{p})
+	http.Handle("/print", &printHandler{p})
 	http.ListenAndServe(":40404", nil)
 }
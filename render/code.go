@@ -0,0 +1,36 @@
+package render
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+)
+
+// renderCode encodes a Code and scales it to a square (QR) or
+// height-matching strip (1D barcode) of the given height, in pixels.
+func renderCode(c Code, heightPx int) (image.Image, error) {
+	var bc barcode.Barcode
+	var err error
+	switch c.Kind {
+	case CodeQR:
+		bc, err = qr.Encode(c.Content, qr.M, qr.Auto)
+	case CodeCode128:
+		bc, err = code128.Encode(c.Content)
+	default:
+		return nil, fmt.Errorf("unknown code kind %d", c.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode %q: %v", c.Content, err)
+	}
+
+	width := heightPx
+	if bc.Metadata().Dimensions == 1 {
+		// 1D barcodes are wide strips, not squares; give them some room to
+		// be scannable rather than squashing them into heightPx x heightPx.
+		width = heightPx * 3
+	}
+	return barcode.Scale(bc, width, heightPx)
+}
@@ -0,0 +1,161 @@
+// Package render turns label content (text, and optionally a QR code or
+// barcode) into an image.Image sized for a specific tape width, replacing
+// the ImageMagick "convert" subprocess that ptprint used to shell out to.
+// Preview and print both use this package, so what's previewed is exactly
+// what gets printed.
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Alignment selects how a line of text is positioned across the tape width.
+type Alignment int
+
+const (
+	AlignCenter Alignment = iota
+	AlignLeft
+	AlignRight
+)
+
+// Code selects an optional QR code or barcode to render alongside the text.
+type Code struct {
+	Kind    CodeKind
+	Content string
+}
+
+// CodeKind identifies the kind of 1D/2D code a Code should render as.
+type CodeKind int
+
+const (
+	CodeQR CodeKind = iota
+	CodeCode128
+)
+
+// Options controls how a label is rendered.
+type Options struct {
+	// HeightPx is the tape's printable width, in pixels (the image's height,
+	// since labels are rendered before the 90-degree rotation onto tape).
+	HeightPx int
+	// FontSizePt is the point size text is rendered at.
+	FontSizePt float64
+	// Align selects how each line of text is positioned.
+	Align Alignment
+	// Code, if non-nil, is rendered to the right of the text.
+	Code *Code
+}
+
+var (
+	baseFontOnce sync.Once
+	baseFont     *opentype.Font
+	baseFontErr  error
+)
+
+func loadBaseFont() (*opentype.Font, error) {
+	baseFontOnce.Do(func() {
+		baseFont, baseFontErr = opentype.Parse(goregular.TTF)
+	})
+	return baseFont, baseFontErr
+}
+
+func faceForSize(pt float64) (font.Face, error) {
+	f, err := loadBaseFont()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse embedded font: %v", err)
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size: pt,
+		DPI:  72,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create font face: %v", err)
+	}
+	return face, nil
+}
+
+// Render draws text (optionally alongside a QR code or barcode) into an
+// image.Image HeightPx tall, wide enough to fit the content, white
+// background with black foreground, matching what the old
+// "convert ... label:" pipeline produced.
+func Render(text string, opts Options) (image.Image, error) {
+	if opts.HeightPx <= 0 {
+		return nil, fmt.Errorf("HeightPx must be positive, got %d", opts.HeightPx)
+	}
+	face, err := faceForSize(opts.FontSizePt)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	lines := strings.Split(text, "\n")
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+	textWidth := 0
+	for _, l := range lines {
+		if w := font.MeasureString(face, l).Ceil(); w > textWidth {
+			textWidth = w
+		}
+	}
+
+	var codeImg image.Image
+	if opts.Code != nil {
+		codeImg, err = renderCode(*opts.Code, opts.HeightPx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to render code: %v", err)
+		}
+	}
+
+	codeWidth := 0
+	if codeImg != nil {
+		codeWidth = codeImg.Bounds().Dx()
+	}
+	gap := 0
+	if textWidth > 0 && codeWidth > 0 {
+		gap = opts.HeightPx / 8
+	}
+	width := textWidth + gap + codeWidth
+	if width < 1 {
+		width = 1
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, opts.HeightPx))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	if codeImg != nil {
+		r := image.Rect(textWidth+gap, 0, width, opts.HeightPx)
+		draw.Draw(img, r, codeImg, image.Point{}, draw.Over)
+	}
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.Black,
+		Face: face,
+	}
+	textHeight := lineHeight * len(lines)
+	y := opts.HeightPx - textHeight + metrics.Ascent.Ceil()
+	for _, l := range lines {
+		w := font.MeasureString(face, l).Ceil()
+		var x int
+		switch opts.Align {
+		case AlignLeft:
+			x = 0
+		case AlignRight:
+			x = textWidth - w
+		default:
+			x = (textWidth - w) / 2
+		}
+		d.Dot = fixed.P(x, y)
+		d.DrawString(l)
+		y += lineHeight
+	}
+	return img, nil
+}